@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"time"
 )
 
 // Commands is a command executor
@@ -20,11 +21,15 @@ type Command interface {
 
 func New(dir string) *Exec {
 	return &Exec{
-		Dir:    dir,
-		Env:    os.Environ(),
-		Stderr: os.Stderr,
-		Stdout: os.Stdout,
-		Stdin:  os.Stdin,
+		Dir:          dir,
+		Env:          os.Environ(),
+		Stderr:       os.Stderr,
+		Stdout:       os.Stdout,
+		Stdin:        os.Stdin,
+		StopSignal:   os.Interrupt,
+		StopGrace:    DefaultStopGrace,
+		KillGrace:    DefaultKillGrace,
+		ProcessGroup: true,
 	}
 }
 
@@ -34,6 +39,33 @@ type Exec struct {
 	Stderr io.Writer
 	Stdout io.Writer
 	Stdin  io.Reader
+
+	// StopSignal is the signal Process.Stop sends first. Defaults to
+	// os.Interrupt.
+	StopSignal os.Signal
+
+	// StopGrace is how long Process.Stop waits after StopSignal before
+	// escalating to SIGQUIT (or, on Windows, TerminateProcess) so that Go
+	// child processes get a chance to dump their goroutine stack traces.
+	// Defaults to DefaultStopGrace.
+	StopGrace time.Duration
+
+	// KillGrace is how long Process.Stop waits after the SIGQUIT escalation
+	// before falling back to os.Kill. Defaults to DefaultKillGrace.
+	KillGrace time.Duration
+
+	// ProcessGroup puts each Cmd's process in its own process group (Unix)
+	// or Job Object (Windows), so Stop/Kill signal the whole tree instead of
+	// just the direct child. Defaults to true.
+	ProcessGroup bool
+
+	// PTY allocates a pseudo-terminal for each Cmd's process instead of
+	// plain pipes, wiring it up as stdin/stdout/stderr. Use Process.PTY to
+	// read/write it and Process.Resize to forward terminal resizes.
+	// Defaults to false; override per-Cmd with Cmd.SetPTY. Not currently
+	// supported on Windows (creack/pty has no ConPTY backend yet); Start
+	// returns an error there instead.
+	PTY bool
 }
 
 var _ Commands = (*Exec)(nil)
@@ -45,7 +77,15 @@ func (c *Exec) Command(name string, args ...string) *Cmd {
 	cmd.Stderr = c.Stderr
 	cmd.Stdout = c.Stdout
 	cmd.Stdin = c.Stdin
-	return (*Cmd)(cmd)
+	sc := (*Cmd)(cmd)
+	setCmdConfig(sc, cmdConfig{
+		signal:       c.StopSignal,
+		stopGrace:    c.StopGrace,
+		killGrace:    c.KillGrace,
+		processGroup: c.ProcessGroup,
+		pty:          c.PTY,
+	})
+	return sc
 }
 
 type Cmd exec.Cmd
@@ -56,12 +96,108 @@ func (c *Cmd) exec() *exec.Cmd {
 	return (*exec.Cmd)(c)
 }
 
+// SetPTY overrides, for this Cmd only, whether Start allocates a
+// pseudo-terminal instead of plain pipes. Must be called before Start. See
+// Exec.PTY for the current Windows limitation.
+func (c *Cmd) SetPTY(enabled bool) {
+	cfg := peekCmdConfig(c)
+	cfg.pty = enabled
+	setCmdConfig(c, cfg)
+}
+
+// OnStdoutLine registers fn to be called once per line of stdout while the
+// process runs. fn runs on its own goroutine, fed by a bounded queue: a
+// callback that falls behind drops lines rather than slowing down the
+// process's actual output, and Process.DroppedStdoutLines reports how many.
+// Must be called before Start. Not applied when the Cmd is started with a
+// PTY, since stdin/stdout/stderr there are a single combined stream.
+func (c *Cmd) OnStdoutLine(fn func(line []byte)) {
+	cfg := peekCmdConfig(c)
+	cfg.stdoutFn = fn
+	setCmdConfig(c, cfg)
+}
+
+// OnStderrLine is OnStdoutLine for stderr; see Process.DroppedStderrLines.
+func (c *Cmd) OnStderrLine(fn func(line []byte)) {
+	cfg := peekCmdConfig(c)
+	cfg.stderrFn = fn
+	setCmdConfig(c, cfg)
+}
+
+// Tee makes the process's stdout and stderr also write to stdout/stderr, in
+// addition to the Cmd's existing Stdout/Stderr and any OnStdoutLine/
+// OnStderrLine callback. Either argument may be nil to leave that stream
+// alone. Must be called before Start.
+func (c *Cmd) Tee(stdout, stderr io.Writer) {
+	cfg := peekCmdConfig(c)
+	cfg.teeStdout = stdout
+	cfg.teeStderr = stderr
+	setCmdConfig(c, cfg)
+}
+
 func (c *Cmd) Start() (*Process, error) {
-	if err := c.exec().Start(); err != nil {
+	cfg := popCmdConfig(c)
+
+	// A PTY's slave already puts the child in its own session (and thus its
+	// own process group), so skip the separate Setpgid step.
+	if cfg.processGroup && !cfg.pty {
+		setProcessGroup(c.exec())
+	}
+
+	// Captured before any of the composition below touches Stdout/Stderr,
+	// so Process.Restart can rebuild the next generation's wiring from the
+	// caller's actual writers instead of the already-composed (and, for a
+	// line-callback Cmd, already-closed) ones left on cmd.Stdout/Stderr.
+	origStdout, origStderr := c.exec().Stdout, c.exec().Stderr
+
+	var stdoutLS, stderrLS *lineStream
+	if !cfg.pty {
+		ce := c.exec()
+		switch {
+		case cfg.stdoutFn != nil:
+			ce.Stdout, stdoutLS = newLineStream(combinedWriter(origStdout, cfg.teeStdout), cfg.stdoutFn)
+		case cfg.teeStdout != nil:
+			ce.Stdout = combinedWriter(origStdout, cfg.teeStdout)
+		}
+		switch {
+		case cfg.stderrFn != nil:
+			ce.Stderr, stderrLS = newLineStream(combinedWriter(origStderr, cfg.teeStderr), cfg.stderrFn)
+		case cfg.teeStderr != nil:
+			ce.Stderr = combinedWriter(origStderr, cfg.teeStderr)
+		}
+	}
+
+	var ptyFile *os.File
+	if cfg.pty {
+		// ptyStart only wires its pty slave into Stdin/Stdout/Stderr when
+		// they're nil, but Exec.Command always sets them to os.Std{in,out,
+		// err} by default — clear them first or the child's real stdio
+		// never goes through the pty at all.
+		ce := c.exec()
+		ce.Stdin, ce.Stdout, ce.Stderr = nil, nil, nil
+		f, err := ptyStart(ce)
+		if err != nil {
+			return nil, err
+		}
+		ptyFile = f
+	} else if err := c.exec().Start(); err != nil {
 		return nil, err
 	}
-	p := newProcess(c)
-	go p.wait()
+
+	var pg processGroup
+	if cfg.processGroup {
+		// Best-effort: if we can't set up group tracking, Stop/Kill just
+		// fall back to signaling the direct child.
+		pg, _ = newProcessGroup(c.exec())
+	}
+	p := newProcess(c, cfg, pg, ptyFile, stdoutLS, stderrLS, origStdout, origStderr)
+	// A Cmd with a raw StdoutPipe/StderrPipe needs its caller to finish
+	// draining those before the process is waited on (os/exec's Wait closes
+	// them), so defer the wait goroutine until Process.Wait/Stop/Kill is
+	// actually called instead of racing it against that drain.
+	if !cfg.rawPipe {
+		p.startWait()
+	}
 	return p, nil
 }
 
@@ -72,3 +208,31 @@ func (c *Cmd) Run(ctx context.Context) error {
 	}
 	return p.Wait(ctx)
 }
+
+// StdoutPipe returns a pipe connected to the process's standard output,
+// wired up before Start. Must be called before Start. As with os/exec, the
+// caller must finish reading before calling Process.Wait/Stop/Kill: Start
+// defers its own internal wait for a Cmd that's had StdoutPipe/StderrPipe
+// called on it, so draining the pipe can no longer race the pipe being
+// closed out from under the reader.
+func (c *Cmd) StdoutPipe() (io.ReadCloser, error) {
+	cfg := peekCmdConfig(c)
+	cfg.rawPipe = true
+	setCmdConfig(c, cfg)
+	return c.exec().StdoutPipe()
+}
+
+// StderrPipe returns a pipe connected to the process's standard error, with
+// the same before-Start requirement and wait-deferral as StdoutPipe.
+func (c *Cmd) StderrPipe() (io.ReadCloser, error) {
+	cfg := peekCmdConfig(c)
+	cfg.rawPipe = true
+	setCmdConfig(c, cfg)
+	return c.exec().StderrPipe()
+}
+
+// StdinPipe returns a pipe connected to the process's standard input. The
+// caller must close it once done writing, or the process may not exit.
+func (c *Cmd) StdinPipe() (io.WriteCloser, error) {
+	return c.exec().StdinPipe()
+}
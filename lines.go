@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"sync/atomic"
+)
+
+// lineQueueSize bounds how many scanned lines can be queued ahead of a slow
+// callback. Once full, newer lines are dropped rather than applying
+// backpressure to the process's actual output, which must keep draining so
+// the child never blocks on a full pipe.
+const lineQueueSize = 256
+
+// lineStream scans a process's output line by line on a dedicated goroutine
+// and delivers each line to fn on a second dedicated goroutine, so a slow
+// callback can't stall the scan.
+type lineStream struct {
+	pw      *io.PipeWriter
+	done    chan struct{}
+	dropped uint64 // atomic
+}
+
+// newLineStream returns the io.Writer to plug in as the process's
+// stdout/stderr (wired to dest too, if dest is non-nil) and the lineStream
+// tracking its scan/callback goroutines. fn must be non-nil.
+func newLineStream(dest io.Writer, fn func(line []byte)) (io.Writer, *lineStream) {
+	pr, pw := io.Pipe()
+
+	ls := &lineStream{pw: pw, done: make(chan struct{})}
+	queue := make(chan []byte, lineQueueSize)
+
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case queue <- line:
+			default:
+				atomic.AddUint64(&ls.dropped, 1)
+			}
+		}
+		close(queue)
+		// Scan stops early on a line over the 1MiB buffer limit; discard the
+		// rest of the output rather than leave the writer blocked on the pipe.
+		io.Copy(io.Discard, pr)
+	}()
+
+	go func() {
+		defer close(ls.done)
+		for line := range queue {
+			fn(line)
+		}
+	}()
+
+	out := io.Writer(pw)
+	if dest != nil {
+		out = io.MultiWriter(pw, dest)
+	}
+	return out, ls
+}
+
+// close closes the pipe so the scan goroutine sees EOF, then blocks until
+// the callback goroutine has drained every queued line.
+func (ls *lineStream) close() {
+	ls.pw.Close()
+	<-ls.done
+}
+
+// droppedLines reports how many lines were discarded because the callback
+// fell behind the queue.
+func (ls *lineStream) droppedLines() uint64 {
+	return atomic.LoadUint64(&ls.dropped)
+}
+
+// combinedWriter returns a writer that fans out to both a and b, whichever
+// of the two are non-nil, or nil if neither is set.
+func combinedWriter(a, b io.Writer) io.Writer {
+	switch {
+	case a != nil && b != nil:
+		return io.MultiWriter(a, b)
+	case a != nil:
+		return a
+	case b != nil:
+		return b
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCmdOnStdoutLine(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stdout, cmd.Stderr = nil, nil
+	c := shellCommand(t, cmd, "echo one; echo two; echo three")
+
+	var mu sync.Mutex
+	var lines []string
+	c.OnStdoutLine(func(line []byte) {
+		mu.Lock()
+		lines = append(lines, string(line))
+		mu.Unlock()
+	})
+
+	p, err := c.Start()
+	is.NoErr(err)
+	is.NoErr(p.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(strings.Join(lines, ","), "one,two,three")
+	is.Equal(p.DroppedStdoutLines(), uint64(0))
+}
+
+func TestCmdTee(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stdout, cmd.Stderr = nil, nil
+	c := shellCommand(t, cmd, "echo out; echo err 1>&2")
+
+	var stdout, stderr bytes.Buffer
+	c.Tee(&stdout, &stderr)
+
+	var gotOut string
+	c.OnStdoutLine(func(line []byte) { gotOut = string(line) })
+
+	p, err := c.Start()
+	is.NoErr(err)
+	is.NoErr(p.Wait(context.Background()))
+
+	is.Equal(strings.TrimSpace(stdout.String()), "out")
+	is.Equal(strings.TrimSpace(stderr.String()), "err")
+	is.Equal(gotOut, "out")
+}
@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// maxCapturedStderr caps how much stderr Output retains for ExitError,
+// matching the 32KiB stdlib os/exec uses for the same purpose.
+const maxCapturedStderr = 32 * 1024
+
+// ExitError is returned by Cmd.Output when the command exits with an error
+// and Stderr wasn't already set, so callers can inspect why it failed
+// without wiring up their own buffer.
+type ExitError struct {
+	// Err is the error Run returned, usually an *exec.ExitError.
+	Err error
+	// Stderr holds up to the last maxCapturedStderr bytes the process wrote
+	// to stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// Output runs the command and returns its standard output. Stdout must not
+// already be set to anything other than the os.Stdout Exec installs by
+// default. If Stderr also isn't set (or is likewise still the default) and
+// the command fails, the returned error is an *ExitError carrying the tail
+// of stderr.
+func (c *Cmd) Output(ctx context.Context) ([]byte, error) {
+	if !isUnsetOutput(c.Stdout, os.Stdout) {
+		return nil, errors.New("shell: Stdout already set")
+	}
+
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+
+	captureStderr := isUnsetOutput(c.Stderr, os.Stderr)
+	var stderr tailWriter
+	if captureStderr {
+		stderr.max = maxCapturedStderr
+		c.Stderr = &stderr
+	}
+
+	if err := c.Run(ctx); err != nil {
+		if captureStderr {
+			err = &ExitError{Err: err, Stderr: stderr.Bytes()}
+		}
+		return stdout.Bytes(), err
+	}
+	return stdout.Bytes(), nil
+}
+
+// CombinedOutput runs the command and returns its combined standard output
+// and standard error. Stdout and Stderr must not already be set to anything
+// other than the os.Stdout/os.Stderr Exec installs by default.
+func (c *Cmd) CombinedOutput(ctx context.Context) ([]byte, error) {
+	if !isUnsetOutput(c.Stdout, os.Stdout) {
+		return nil, errors.New("shell: Stdout already set")
+	}
+	if !isUnsetOutput(c.Stderr, os.Stderr) {
+		return nil, errors.New("shell: Stderr already set")
+	}
+
+	var buf bytes.Buffer
+	c.Stdout = &buf
+	c.Stderr = &buf
+
+	err := c.Run(ctx)
+	return buf.Bytes(), err
+}
+
+// isUnsetOutput reports whether w is nil or still exactly the Exec-
+// installed default (os.Stdout/os.Stderr), as opposed to a writer the
+// caller wired up themselves to receive output directly — in which case
+// Output/CombinedOutput must not also capture into it.
+func isUnsetOutput(w, def io.Writer) bool {
+	return w == nil || w == def
+}
+
+// tailWriter retains only the most recently written max bytes, so Output
+// can expose a bounded tail of stderr instead of buffering it unbounded.
+type tailWriter struct {
+	max int
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.max <= 0 {
+		return n, nil
+	}
+	if len(p) >= w.max {
+		w.buf = append(w.buf[:0:0], p[len(p)-w.max:]...)
+		return n, nil
+	}
+	if over := len(w.buf) + len(p) - w.max; over > 0 {
+		w.buf = append(w.buf[:0], w.buf[over:]...)
+	}
+	w.buf = append(w.buf, p...)
+	return n, nil
+}
+
+func (w *tailWriter) Bytes() []byte { return w.buf }
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCmdOutput(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	out, err := shellCommand(t, cmd, "echo hello").Output(context.Background())
+	is.NoErr(err)
+	is.Equal(strings.TrimRight(string(out), "\r\n"), "hello")
+}
+
+func TestCmdOutputExitErrorCapturesStderr(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	_, err := shellCommand(t, cmd, "echo oops 1>&2; exit 3").Output(context.Background())
+	is.Equal(err == nil, false)
+
+	var exitErr *ExitError
+	is.Equal(errors.As(err, &exitErr), true)
+	is.Equal(strings.TrimRight(string(exitErr.Stderr), "\r\n"), "oops")
+}
+
+func TestCmdCombinedOutput(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	out, err := shellCommand(t, cmd, "echo out; echo err 1>&2").CombinedOutput(context.Background())
+	is.NoErr(err)
+	is.Equal(strings.Contains(string(out), "out"), true)
+	is.Equal(strings.Contains(string(out), "err"), true)
+}
+
+func TestCmdStdoutPipe(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	c := shellCommand(t, cmd, "echo piped")
+	c.Stdout = nil
+
+	stdout, err := c.StdoutPipe()
+	is.NoErr(err)
+
+	p, err := c.Start()
+	is.NoErr(err)
+
+	scanner := bufio.NewScanner(stdout)
+	is.Equal(scanner.Scan(), true)
+	is.Equal(strings.TrimSpace(scanner.Text()), "piped")
+
+	is.NoErr(p.Wait(context.Background()))
+}
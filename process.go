@@ -3,18 +3,107 @@ package shell
 import (
 	"context"
 	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Default grace periods used by Process.Stop between each stage of the
+// escalating shutdown: StopSignal, then the stack-dump signal, then Kill.
+const (
+	DefaultStopGrace = 5 * time.Second
+	DefaultKillGrace = 5 * time.Second
+)
+
+// cmdConfig carries the Exec-level settings from Exec.Command through to
+// the Process created by Cmd.Start. Cmd is a bare redefinition of exec.Cmd
+// (so that callers can convert an *exec.Cmd to a *Cmd directly), so there's
+// no room on Cmd itself to carry this configuration.
+type cmdConfig struct {
+	signal       os.Signal
+	stopGrace    time.Duration
+	killGrace    time.Duration
+	processGroup bool
+	pty          bool
+
+	// rawPipe is set once StdoutPipe/StderrPipe has been called, so Start
+	// knows to defer its own wait for the process until the caller is done
+	// draining them (see Cmd.StdoutPipe).
+	rawPipe bool
+
+	// stdoutFn/stderrFn are the callbacks registered via Cmd.OnStdoutLine/
+	// Cmd.OnStderrLine, and teeStdout/teeStderr the extra writers registered
+	// via Cmd.Tee. Left nil, Start doesn't wire up a lineStream at all.
+	stdoutFn  func(line []byte)
+	stderrFn  func(line []byte)
+	teeStdout io.Writer
+	teeStderr io.Writer
+}
+
+var cmdConfigs sync.Map // map[*Cmd]cmdConfig
+
+func setCmdConfig(c *Cmd, cfg cmdConfig) {
+	cmdConfigs.Store(c, cfg)
+}
+
+// peekCmdConfig reads the config stored for c, if any, without clearing it.
+// Used by per-Cmd overrides (e.g. Cmd.SetPTY) that run before Start.
+func peekCmdConfig(c *Cmd) cmdConfig {
+	cfg, _ := cmdConfigs.Load(c)
+	sc, _ := cfg.(cmdConfig)
+	return sc
+}
+
+// popCmdConfig reads and clears the config for c, filling in defaults for
+// anything left unset (including Cmds created without going through an
+// Exec, which never have an entry at all).
+func popCmdConfig(c *Cmd) cmdConfig {
+	cfg, _ := cmdConfigs.LoadAndDelete(c)
+	sc, _ := cfg.(cmdConfig)
+	if sc.signal == nil {
+		sc.signal = os.Interrupt
+	}
+	if sc.stopGrace <= 0 {
+		sc.stopGrace = DefaultStopGrace
+	}
+	if sc.killGrace <= 0 {
+		sc.killGrace = DefaultKillGrace
+	}
+	return sc
+}
+
+// processGroup lets Stop/Kill signal an entire process tree instead of just
+// the direct child: a pgid-targeted kill on Unix, a Job Object on Windows.
+type processGroup interface {
+	// Pgid reports the Unix process group id, if there is one.
+	Pgid() (int, bool)
+	Signal(sig os.Signal) error
+}
+
 // Wrap a command in a process
-func newProcess(cmd *Cmd) *Process {
+func newProcess(cmd *Cmd, cfg cmdConfig, pg processGroup, pty *os.File, stdoutLines, stderrLines *lineStream, origStdout, origStderr io.Writer) *Process {
 	return &Process{
 		cmd: cmd,
 		// Buffer one exit value so the wait goroutine can always complete,
 		// even if callers stop/restart after the process has already exited.
-		exitCh: make(chan error, 1),
+		exitCh:       make(chan error, 1),
+		signal:       cfg.signal,
+		stopGrace:    cfg.stopGrace,
+		killGrace:    cfg.killGrace,
+		processGroup: cfg.processGroup,
+		pg:           pg,
+		pty:          pty,
+		stdoutFn:     cfg.stdoutFn,
+		stderrFn:     cfg.stderrFn,
+		teeStdout:    cfg.teeStdout,
+		teeStderr:    cfg.teeStderr,
+		stdoutLines:  stdoutLines,
+		stderrLines:  stderrLines,
+		origStdout:   origStdout,
+		origStderr:   origStderr,
 	}
 }
 
@@ -29,69 +118,197 @@ func (e *onceError) Do(fn func() error) (err error) {
 }
 
 type Process struct {
-	cmd    *Cmd
-	exitCh chan error
-	once   onceError
+	cmd      *Cmd
+	exitCh   chan error
+	once     onceError
+	waitOnce sync.Once
+
+	signal    os.Signal
+	stopGrace time.Duration
+	killGrace time.Duration
+
+	processGroup bool
+	pg           processGroup
+
+	pty *os.File
+
+	stdoutFn  func(line []byte)
+	stderrFn  func(line []byte)
+	teeStdout io.Writer
+	teeStderr io.Writer
+
+	stdoutLines *lineStream
+	stderrLines *lineStream
+
+	// origStdout/origStderr are the writers the caller actually configured
+	// (possibly nil, possibly Exec's os.Stdout/os.Stderr default) before
+	// Start composed them with any tee/line-callback wiring. Restart reuses
+	// these to rebuild the next generation's Stdout/Stderr, since cmd.Stdout/
+	// cmd.Stderr themselves may by now be an internal lineStream pipe this
+	// generation's wait already closed.
+	origStdout io.Writer
+	origStderr io.Writer
+}
+
+// startWait spawns the goroutine that reaps the process and feeds exitCh,
+// unless one is already running or has already run. Cmd.Start calls this
+// immediately except when the Cmd has a raw StdoutPipe/StderrPipe, in
+// which case it's deferred until Wait/stop/kill is actually called, so
+// reaping the process (which closes those pipes) can't race the caller
+// still draining them.
+func (p *Process) startWait() {
+	p.waitOnce.Do(func() { go p.wait() })
 }
 
+// wait blocks until the process exits and, if line callbacks were
+// registered, until their scan/callback goroutines have drained every line
+// already written. This is what makes Wait's return imply all callbacks
+// have fired rather than racing the last few lines.
 func (p *Process) wait() {
-	p.exitCh <- p.cmd.exec().Wait()
+	err := p.cmd.exec().Wait()
+	if p.stdoutLines != nil {
+		p.stdoutLines.close()
+	}
+	if p.stderrLines != nil {
+		p.stderrLines.close()
+	}
+	p.exitCh <- err
+}
+
+// DroppedStdoutLines reports how many stdout lines were discarded because
+// OnStdoutLine's callback fell behind, or 0 if no callback was registered.
+func (p *Process) DroppedStdoutLines() uint64 {
+	if p.stdoutLines == nil {
+		return 0
+	}
+	return p.stdoutLines.droppedLines()
+}
+
+// DroppedStderrLines reports how many stderr lines were discarded because
+// OnStderrLine's callback fell behind, or 0 if no callback was registered.
+func (p *Process) DroppedStderrLines() uint64 {
+	if p.stderrLines == nil {
+		return 0
+	}
+	return p.stderrLines.droppedLines()
+}
+
+// Pgid reports the Unix process group id the child was started in, if
+// process-group management is enabled and the platform supports it.
+func (p *Process) Pgid() (int, bool) {
+	if p.pg == nil {
+		return 0, false
+	}
+	return p.pg.Pgid()
+}
+
+// PTY returns the pseudo-terminal master file the child's stdin/stdout/
+// stderr are wired to, or nil if it wasn't started with Exec.PTY/Cmd.SetPTY.
+// Reads and writes to it carry the child's terminal I/O.
+func (p *Process) PTY() *os.File {
+	return p.pty
 }
 
-// Stop the process. We first try interrupting. If the context is canceled
-// while waiting, we switch to kill.
-func (p *Process) stop(ctx context.Context) error {
-	sp := p.cmd.Process
-	if sp == nil {
+// Resize tells the pseudo-terminal about a new terminal size, forwarding a
+// SIGWINCH-driven resize to the child. Returns an error if the process
+// wasn't started with a PTY.
+func (p *Process) Resize(rows, cols uint16) error {
+	if p.pty == nil {
+		return errors.New("shell: process was not started with a PTY")
+	}
+	return ptyResize(p.pty, rows, cols)
+}
+
+// signalProcess sends sig to the process, or to its whole process group
+// when process-group management is enabled.
+func (p *Process) signalProcess(sig os.Signal) error {
+	if p.pg != nil {
+		return p.pg.Signal(sig)
+	}
+	return p.cmd.Process.Signal(sig)
+}
+
+// stop runs the three-stage escalating shutdown: signal, then (after
+// stopGrace) the stack-dump signal (SIGQUIT on Unix, TerminateProcess on
+// Windows), then (after killGrace) Kill. A canceled ctx escalates to the
+// next stage immediately rather than jumping straight to Kill, so the
+// stack-dump signal is still sent; once ctx is done, though, Kill follows
+// it without waiting out killGrace, so a hung child may be killed before
+// its stack dump is complete.
+func (p *Process) stop(ctx context.Context, signal os.Signal, stopGrace, killGrace time.Duration) error {
+	if p.cmd.Process == nil {
 		return nil
 	}
+	p.startWait()
+
+	if err := p.signalProcess(signal); err != nil {
+		if isProcessDone(err) {
+			return nil
+		}
+		return p.kill()
+	}
 
-	// Default to interrupt signal
-	expectError := isInterrupt
-	signal := os.Interrupt
+	if err, done := p.waitStage(ctx, stopGrace); done {
+		return p.finish(err, isSignal(signal))
+	}
 
-	// Send the signal to the process
-	if err := sp.Signal(signal); err != nil {
+	if err := quitSignal(p); err != nil {
 		if isProcessDone(err) {
 			return nil
 		}
-		// If the signal errored, switch to kill
-		expectError = isKilled
-		signal = os.Kill
-		if err := sp.Signal(signal); err != nil {
-			return err
+		return p.kill()
+	}
+
+	if err, done := p.waitStage(ctx, killGrace); done {
+		return p.finish(err, quitExpected)
+	}
+
+	return p.kill()
+}
+
+// waitStage waits for the process to exit or for grace to elapse, whichever
+// comes first. A canceled ctx counts as grace elapsing, so the caller moves
+// on to the next escalation stage instead of stopping early.
+func (p *Process) waitStage(ctx context.Context, grace time.Duration) (err error, exited bool) {
+	if grace <= 0 {
+		select {
+		case err := <-p.exitCh:
+			return err, true
+		default:
+			return nil, false
 		}
 	}
 
-	var err error
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
 	select {
-	// Wait for the process to exit
-	case err = <-p.exitCh:
-	// If the context is canceled, we switch to kill
+	case err := <-p.exitCh:
+		return err, true
 	case <-ctx.Done():
-		return p.kill()
+		return nil, false
+	case <-timer.C:
+		return nil, false
 	}
+}
 
-	// Cleanup the exit channel
+func (p *Process) finish(err error, expectError func(error) bool) error {
 	close(p.exitCh)
-
-	// If we got an error, check if it's expected or not
 	if err != nil && !expectError(err) {
 		return err
 	}
-
 	return nil
 }
 
 // Kill the process. Should only be called once
 func (p *Process) kill() error {
-	sp := p.cmd.Process
-	if sp == nil {
+	if p.cmd.Process == nil {
 		return nil
 	}
+	p.startWait()
 
-	// Send a kill signal to the process
-	if err := sp.Kill(); err != nil {
+	// Send a kill signal to the process (or its whole process group)
+	if err := p.signalProcess(os.Kill); err != nil {
 		if isProcessDone(err) {
 			return nil
 		}
@@ -102,7 +319,7 @@ func (p *Process) kill() error {
 	err := <-p.exitCh
 	close(p.exitCh)
 
-	if err != nil && !isKilled(err) {
+	if err != nil && !isSignalExit(err) {
 		return err
 	}
 	return nil
@@ -110,7 +327,16 @@ func (p *Process) kill() error {
 
 func (p *Process) Stop(ctx context.Context) (err error) {
 	return p.once.Do(func() error {
-		return p.stop(ctx)
+		return p.stop(ctx, p.signal, p.stopGrace, p.killGrace)
+	})
+}
+
+// StopWithGracePeriod runs the same escalating shutdown as Stop, but with
+// grace used for both the pre-SIGQUIT and pre-Kill waits instead of the
+// Process's configured StopGrace/KillGrace.
+func (p *Process) StopWithGracePeriod(ctx context.Context, grace time.Duration) (err error) {
+	return p.once.Do(func() error {
+		return p.stop(ctx, p.signal, grace, grace)
 	})
 }
 
@@ -119,6 +345,7 @@ func (p *Process) Kill() (err error) {
 }
 
 func (p *Process) Wait(ctx context.Context) error {
+	p.startWait()
 	select {
 	case <-ctx.Done():
 		return p.Kill()
@@ -135,12 +362,26 @@ func (p *Process) Restart(ctx context.Context) (*Process, error) {
 	// Re-run the command again. cmd.Args[0] is the path, so we skip that.
 	next := exec.Command(p.cmd.Path, p.cmd.Args[1:]...)
 	next.Env = p.cmd.Env
-	next.Stdout = p.cmd.Stdout
-	next.Stderr = p.cmd.Stderr
+	// p.cmd.Stdout/Stderr may by now be the internal lineStream pipe this
+	// generation's wait already closed, so rebuild from the writers the
+	// caller actually configured rather than reusing those directly.
+	next.Stdout = p.origStdout
+	next.Stderr = p.origStderr
 	next.Stdin = p.cmd.Stdin
 	next.ExtraFiles = p.cmd.ExtraFiles
 	next.Dir = p.cmd.Dir
 	cmd := (*Cmd)(next)
+	setCmdConfig(cmd, cmdConfig{
+		signal:       p.signal,
+		stopGrace:    p.stopGrace,
+		killGrace:    p.killGrace,
+		processGroup: p.processGroup,
+		pty:          p.pty != nil,
+		stdoutFn:     p.stdoutFn,
+		stderrFn:     p.stderrFn,
+		teeStdout:    p.teeStdout,
+		teeStderr:    p.teeStderr,
+	})
 	return cmd.Start()
 }
 
@@ -148,10 +389,28 @@ func isProcessDone(err error) bool {
 	return errors.Is(err, os.ErrProcessDone)
 }
 
-func isInterrupt(err error) bool {
-	return err != nil && err.Error() == `signal: interrupt`
-}
-
 func isKilled(err error) bool {
 	return err != nil && err.Error() == `signal: killed`
 }
+
+// isSignalExit reports whether err is the "signal: ..." error os/exec
+// returns when a process is terminated by any signal. kill sends SIGKILL
+// to reap the process (or its whole group), but when grouped, another
+// member of the tree may already have been terminated by an earlier
+// escalation stage — e.g. a backgrounded child ignores SIGINT/SIGQUIT but
+// the shell running it doesn't, so exec.Cmd.Wait can report that earlier
+// signal's exit status once Kill finally unblocks it (waiting on output
+// a surviving grandchild was still holding open). Either way the process
+// is gone because kill asked it to be, so any signal-terminated exit
+// counts as expected, not just "signal: killed".
+func isSignalExit(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "signal: ")
+}
+
+// isSignal reports whether err is the "signal: ..." error os/exec returns
+// when a process is terminated by sig.
+func isSignal(sig os.Signal) func(error) bool {
+	return func(err error) bool {
+		return err != nil && err.Error() == "signal: "+sig.String()
+	}
+}
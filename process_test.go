@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -70,6 +72,36 @@ func TestProcessRestart(t *testing.T) {
 	is.Equal(strings.Count(out.String(), "restart-ok"), 2)
 }
 
+func TestProcessRestartWithLineCallback(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stdout, cmd.Stderr = nil, nil
+	c := shellCommand(t, cmd, "echo restart-ok")
+
+	var mu sync.Mutex
+	var lines []string
+	c.OnStdoutLine(func(line []byte) {
+		mu.Lock()
+		lines = append(lines, string(line))
+		mu.Unlock()
+	})
+
+	p, err := c.Start()
+	is.NoErr(err)
+	is.NoErr(p.Wait(context.Background()))
+
+	// By now cmd.Stdout is the internal lineStream pipe from the first
+	// generation, already closed by its wait; Restart must not reuse it
+	// as-is or the second generation's Wait fails with a closed-pipe error.
+	next, err := p.Restart(context.Background())
+	is.NoErr(err)
+	is.NoErr(next.Wait(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(strings.Count(strings.Join(lines, ","), "restart-ok"), 2)
+}
+
 func TestProcessWaitContextCanceledKillsProcess(t *testing.T) {
 	is := is.New(t)
 	cmd := New("")
@@ -152,6 +184,108 @@ func TestProcessStopContextCancelFallsBackToKill(t *testing.T) {
 	})
 }
 
+func TestExecDefaultStopSettings(t *testing.T) {
+	is := is.New(t)
+	e := New("")
+	is.Equal(e.StopSignal, os.Interrupt)
+	is.Equal(e.StopGrace, DefaultStopGrace)
+	is.Equal(e.KillGrace, DefaultKillGrace)
+	is.Equal(e.ProcessGroup, true)
+}
+
+func TestProcessStopEscalatesToQuitDump(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGQUIT stack dump on windows")
+	}
+	testchild.Run(t, func(t testing.TB, child *exec.Cmd) {
+		is := is.New(t)
+		stderr := new(bytes.Buffer)
+		child.Stdout = io.Discard
+		child.Stderr = stderr
+
+		readyR, readyW, err := os.Pipe()
+		is.NoErr(err)
+		child.ExtraFiles = []*os.File{readyW}
+
+		p, err := ((*Cmd)(child)).Start()
+		is.NoErr(err)
+		readyW.Close()
+
+		// Wait for the child to install its signal handler before sending
+		// the first signal, or it may just die from the default action
+		// instead of surviving to the SIGQUIT escalation this test exists
+		// to verify.
+		_, err = readyR.Read(make([]byte, 1))
+		is.NoErr(err)
+
+		start := time.Now()
+		is.NoErr(p.StopWithGracePeriod(context.Background(), 50*time.Millisecond))
+		is.Equal(time.Since(start) < 2*time.Second, true)
+		is.Equal(strings.Contains(stderr.String(), "goroutine"), true)
+	}, func(t testing.TB) {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt)
+		defer signal.Stop(sigs)
+
+		os.NewFile(3, "ready").Write([]byte{1})
+
+		deadline := time.NewTimer(10 * time.Second)
+		defer deadline.Stop()
+		for {
+			select {
+			case <-sigs:
+				// Ignore interrupt so the parent escalates to SIGQUIT.
+			case <-deadline.C:
+				return
+			}
+		}
+	})
+}
+
+func TestProcessStopKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process group tested via Job Objects, not ps/kill -0")
+	}
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stderr = io.Discard
+
+	c := cmd.Command("sh", "-c", "sleep 300 & echo $!; wait")
+	c.Stdout = nil
+	stdout, err := c.StdoutPipe()
+	is.NoErr(err)
+
+	p, err := c.Start()
+	is.NoErr(err)
+
+	// Read the grandchild's PID straight off the pipe instead of a plain
+	// bytes.Buffer: os/exec's internal stdout-copy goroutine keeps writing
+	// to a Stdout buffer for as long as the grandchild (which inherits the
+	// pipe) is alive, which raced a bare bytes.Buffer read here.
+	scanner := bufio.NewScanner(stdout)
+	is.Equal(scanner.Scan(), true)
+	grandchildPID := strings.TrimSpace(scanner.Text())
+	is.Equal(grandchildPID == "", false)
+
+	is.NoErr(p.Stop(context.Background()))
+	is.NoErr(waitGone(grandchildPID, 2*time.Second))
+}
+
+// waitGone polls kill -0 until pid is gone (including reaped zombies, which
+// still answer kill -0 until their parent waits on them) or deadline elapses.
+func waitGone(pid string, deadline time.Duration) error {
+	start := time.Now()
+	for {
+		if exec.Command("sh", "-c", "! kill -0 "+pid+" 2>/dev/null").Run() == nil {
+			return nil
+		}
+		if time.Since(start) > deadline {
+			return fmt.Errorf("pid %s still alive after %s", pid, deadline)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func shellCommand(t *testing.T, cmd *Exec, script string) *Cmd {
 	t.Helper()
 	if runtime.GOOS == "windows" {
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// quitSignal sends SIGQUIT so that Go child processes dump their goroutine
+// stack traces to stderr before the final Kill.
+func quitSignal(p *Process) error {
+	return p.signalProcess(syscall.SIGQUIT)
+}
+
+func quitExpected(err error) bool {
+	if err == nil {
+		return false
+	}
+	// A child that doesn't catch SIGQUIT itself dies with "signal: quit".
+	// A Go child typically does "catch" it, though not via signal.Notify:
+	// the runtime's default SIGQUIT handling dumps every goroutine's stack
+	// and calls os.Exit(2) itself, which os/exec reports as a plain
+	// "exit status 2" rather than a signal-terminated exit.
+	return err.Error() == `signal: quit` || err.Error() == `exit status 2`
+}
+
+// setProcessGroup puts cmd's future child in its own process group, so a
+// later signal can be fanned out with syscall.Kill(-pgid, sig) to reach
+// grandchildren too. Must be called before Start.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// newProcessGroup looks up the pgid of cmd's just-started process. Must be
+// called after Start.
+func newProcessGroup(cmd *exec.Cmd) (processGroup, error) {
+	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	if err != nil {
+		return nil, err
+	}
+	return unixProcessGroup(pgid), nil
+}
+
+type unixProcessGroup int
+
+func (pg unixProcessGroup) Pgid() (int, bool) {
+	return int(pg), true
+}
+
+func (pg unixProcessGroup) Signal(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return os.ErrInvalid
+	}
+	if err := syscall.Kill(-int(pg), s); err != nil {
+		// Unlike os.Process.Signal, syscall.Kill doesn't know to translate
+		// "no such process" into os.ErrProcessDone once the group is gone.
+		if err == syscall.ESRCH {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+//go:build windows
+
+package shell
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports while a process
+// is still running. x/sys/windows doesn't export the STILL_ACTIVE constant.
+const stillActive = 259
+
+// quitSignal has no SIGQUIT equivalent on Windows, so the intermediate
+// escalation step degrades to terminating the process (or its Job Object)
+// outright.
+func quitSignal(p *Process) error {
+	return p.signalProcess(os.Kill)
+}
+
+func quitExpected(err error) bool {
+	return isKilled(err)
+}
+
+// setProcessGroup is a no-op on Windows: a process can only be assigned to
+// a Job Object after it exists, so that happens in newProcessGroup instead.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// newProcessGroup creates a Job Object with JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+// and assigns cmd's just-started process to it, so closing the job reaps the
+// whole tree. Must be called after Start.
+func newProcessGroup(cmd *exec.Cmd) (processGroup, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	); err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+
+	handle, err := windows.OpenProcess(windows.PROCESS_ALL_ACCESS, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+
+	if err := windows.AssignProcessToJobObject(job, handle); err != nil {
+		windows.CloseHandle(handle)
+		windows.CloseHandle(job)
+		return nil, err
+	}
+
+	return &windowsProcessGroup{job: job, process: handle}, nil
+}
+
+type windowsProcessGroup struct {
+	job     windows.Handle
+	process windows.Handle
+}
+
+func (pg *windowsProcessGroup) Pgid() (int, bool) {
+	return 0, false
+}
+
+func (pg *windowsProcessGroup) Signal(sig os.Signal) error {
+	var code uint32
+	if err := windows.GetExitCodeProcess(pg.process, &code); err == nil && code != stillActive {
+		return os.ErrProcessDone
+	}
+	if sig != os.Kill {
+		// Windows has no group-wide signal other than termination, so
+		// report it as undelivered rather than silently succeeding — the
+		// caller (Process.stop) escalates straight to the next stage, the
+		// same as the non-grouped path errors immediately for the same
+		// signal.
+		return errors.New("shell: process group signal not supported on windows")
+	}
+	// Closing the job, created with JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE, kills
+	// every process still assigned to it.
+	windows.CloseHandle(pg.process)
+	return windows.CloseHandle(pg.job)
+}
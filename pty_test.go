@@ -0,0 +1,30 @@
+package shell
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestProcessPTY(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ConPTY I/O semantics differ from a Unix pty master")
+	}
+	is := is.New(t)
+	cmd := New("")
+	c := cmd.Command("sh", "-c", "echo pty-ok")
+	c.SetPTY(true)
+
+	p, err := c.Start()
+	is.NoErr(err)
+	is.NoErr(p.Resize(24, 80))
+
+	buf := make([]byte, 256)
+	n, _ := p.PTY().Read(buf)
+	is.Equal(strings.Contains(string(buf[:n]), "pty-ok"), true)
+
+	is.NoErr(p.Wait(context.Background()))
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// ptyStart allocates a pseudo-terminal and starts cmd attached to its slave
+// side as stdin/stdout/stderr, returning the master.
+func ptyStart(cmd *exec.Cmd) (*os.File, error) {
+	return pty.Start(cmd)
+}
+
+// ptyResize tells the pseudo-terminal about a new size.
+func ptyResize(f *os.File, rows, cols uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Rows: rows, Cols: cols})
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package shell
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// errPTYUnsupported is returned by ptyStart/ptyResize on Windows:
+// creack/pty@v1.1.21's start_windows.go is a stub that always returns
+// ErrUnsupported, so there is no ConPTY backend to wire up here yet.
+var errPTYUnsupported = errors.New("shell: PTY is not supported on windows yet")
+
+func ptyStart(cmd *exec.Cmd) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+func ptyResize(f *os.File, rows, cols uint16) error {
+	return errPTYUnsupported
+}
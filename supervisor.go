@@ -0,0 +1,343 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// RestartPolicy controls whether Supervisor restarts its child after it
+// exits.
+type RestartPolicy int
+
+const (
+	// RestartAlways restarts the child no matter how it exited.
+	RestartAlways RestartPolicy = iota
+	// RestartOnFailure only restarts the child if it exited with an error.
+	RestartOnFailure
+	// RestartNever never restarts the child; Run returns once it exits.
+	RestartNever
+)
+
+// Backoff controls the delay Supervisor waits between a child exiting and
+// being restarted.
+type Backoff struct {
+	// Initial is the delay before the first restart. Defaults to 1s.
+	Initial time.Duration
+	// Max caps the delay after repeated doublings. Defaults to 30s.
+	Max time.Duration
+	// Jitter adds up to this much random delay on top of each wait, so a
+	// fleet of supervisors restarting together doesn't thunder back in
+	// lockstep.
+	Jitter time.Duration
+	// ResetAfter is how long a child has to stay up before the backoff
+	// resets to Initial, mirroring systemd's StartupRestartSec reset. A
+	// child that dies immediately after each restart keeps climbing toward
+	// Max; one that runs for a while earns a fresh Initial delay.
+	ResetAfter time.Duration
+}
+
+// EventKind identifies what happened in an Event emitted on Supervisor.Events.
+type EventKind int
+
+const (
+	EventStarted EventKind = iota
+	EventExited
+	EventRestarted
+	EventGaveUp
+)
+
+// Event records something that happened to the child Supervisor owns.
+type Event struct {
+	Kind     EventKind
+	Pid      int
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// Supervisor owns a long-running child and restarts it according to a
+// RestartPolicy, with exponential backoff and an optional HealthCheck that
+// can trigger a restart on its own.
+type Supervisor struct {
+	Policy  RestartPolicy
+	Backoff Backoff
+
+	// MaxRestarts caps how many restarts are allowed within Window before
+	// Run gives up and returns. Zero means unlimited.
+	MaxRestarts int
+	Window      time.Duration
+
+	// HealthCheck, if set, is polled every HealthInterval. After
+	// HealthThreshold consecutive failures, the child is restarted.
+	HealthCheck     func(context.Context, *Process) error
+	HealthInterval  time.Duration
+	HealthThreshold int
+
+	exec *Exec
+	name string
+	args []string
+
+	events chan Event
+
+	mu sync.Mutex
+	// proc and restartCh describe the current generation's child, if any is
+	// running. restartCh is recreated for each generation (see Run) so a
+	// Restart call meant for one generation can't leak into the next and
+	// stop a brand-new child before it's done anything.
+	proc      *Process
+	restartCh chan struct{}
+}
+
+// NewSupervisor builds a Supervisor that runs name/args using e, with
+// sensible restart and backoff defaults (RestartAlways, 1s-30s backoff,
+// 10 restarts per minute before giving up).
+func NewSupervisor(e *Exec, name string, args ...string) *Supervisor {
+	return &Supervisor{
+		Policy: RestartAlways,
+		Backoff: Backoff{
+			Initial:    time.Second,
+			Max:        30 * time.Second,
+			Jitter:     250 * time.Millisecond,
+			ResetAfter: time.Minute,
+		},
+		MaxRestarts:     10,
+		Window:          time.Minute,
+		HealthThreshold: 3,
+		exec:            e,
+		name:            name,
+		args:            args,
+		events:          make(chan Event, 16),
+	}
+}
+
+// Events returns the channel Supervisor emits Started/Exited/Restarted/
+// GaveUp records on. Slow consumers drop events rather than block the
+// supervisor loop.
+func (s *Supervisor) Events() <-chan Event {
+	return s.events
+}
+
+// Restart asks Run to stop the current child and start a fresh one,
+// reusing the same graceful-shutdown path Process.Stop uses so the child
+// gets a chance to flush before it's replaced. A no-op if no child is
+// currently running.
+func (s *Supervisor) Restart() {
+	s.mu.Lock()
+	ch := s.restartCh
+	s.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Signal sends sig to the currently running child, if any.
+func (s *Supervisor) Signal(sig os.Signal) error {
+	p := s.current()
+	if p == nil {
+		return errors.New("shell: supervisor has no running process")
+	}
+	return p.signalProcess(sig)
+}
+
+// Run starts the child and keeps it running according to Policy until ctx
+// is canceled, the child is not restarted (RestartNever, or RestartOnFailure
+// after a clean exit), or MaxRestarts is exceeded within Window.
+func (s *Supervisor) Run(ctx context.Context) error {
+	var restarts []time.Time
+	initial := s.Backoff.Initial
+	if initial <= 0 {
+		initial = time.Second
+	}
+	delay := initial
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		start := time.Now()
+		p, err := s.exec.Command(s.name, s.args...).Start()
+		if err != nil {
+			return err
+		}
+		restartCh := make(chan struct{}, 1)
+		s.setCurrent(p, restartCh)
+		s.emit(Event{Kind: EventStarted, Pid: p.cmd.Process.Pid})
+
+		healthDone := make(chan struct{})
+		if s.HealthCheck != nil {
+			go s.runHealthCheck(ctx, p, healthDone)
+		}
+
+		exitErr, forced := s.waitForExitOrRestart(ctx, p, restartCh)
+		close(healthDone)
+		s.setCurrent(nil, nil)
+
+		uptime := time.Since(start)
+		s.emit(Event{
+			Kind:     EventExited,
+			Pid:      p.cmd.Process.Pid,
+			ExitCode: exitCode(exitErr),
+			Duration: uptime,
+			Err:      exitErr,
+		})
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// A forced restart (manual Restart, or a failed HealthCheck) is a
+		// distinct trigger from the child's own exit status, so it happens
+		// regardless of Policy.
+		if !forced && !s.shouldRestart(exitErr) {
+			return nil
+		}
+
+		now := time.Now()
+		restarts = trimWindow(append(restarts, now), now, s.Window)
+		if s.MaxRestarts > 0 && len(restarts) > s.MaxRestarts {
+			s.emit(Event{Kind: EventGaveUp})
+			return fmt.Errorf("shell: gave up after %d restarts within %s", s.MaxRestarts, s.Window)
+		}
+
+		if s.Backoff.ResetAfter > 0 && uptime >= s.Backoff.ResetAfter {
+			delay = initial
+		}
+
+		wait := delay
+		if s.Backoff.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(s.Backoff.Jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		if s.Backoff.Max > 0 {
+			delay *= 2
+			if delay > s.Backoff.Max {
+				delay = s.Backoff.Max
+			}
+		}
+
+		s.emit(Event{Kind: EventRestarted})
+	}
+}
+
+// waitForExitOrRestart waits for the child to exit on its own, for ctx to be
+// canceled, or for a restart request on restartCh (from Restart or a failed
+// HealthCheck). It reads p.exitCh directly (rather than calling
+// Process.Wait/Stop from separate goroutines) so only one goroutine ever
+// drains that channel at a time. On a restart request, it drives the same
+// graceful-shutdown path Process.Stop uses so the child gets a chance to
+// flush before it's replaced; on ctx cancellation it calls Process.Kill,
+// which kills the child without a grace period. forced reports whether the
+// exit was asked for (restartCh) rather than the child's own doing.
+func (s *Supervisor) waitForExitOrRestart(ctx context.Context, p *Process, restartCh <-chan struct{}) (err error, forced bool) {
+	select {
+	case err := <-p.exitCh:
+		return err, false
+	case <-ctx.Done():
+		return p.Kill(), false
+	case <-restartCh:
+		return p.Stop(ctx), true
+	}
+}
+
+func (s *Supervisor) runHealthCheck(ctx context.Context, p *Process, done <-chan struct{}) {
+	interval := s.HealthInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	threshold := s.HealthThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fails := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.HealthCheck(ctx, p); err != nil {
+				fails++
+				if fails >= threshold {
+					s.Restart()
+					return
+				}
+				continue
+			}
+			fails = 0
+		}
+	}
+}
+
+func (s *Supervisor) shouldRestart(exitErr error) bool {
+	switch s.Policy {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		return exitErr != nil
+	default:
+		return true
+	}
+}
+
+func (s *Supervisor) current() *Process {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proc
+}
+
+func (s *Supervisor) setCurrent(p *Process, restartCh chan struct{}) {
+	s.mu.Lock()
+	s.proc = p
+	s.restartCh = restartCh
+	s.mu.Unlock()
+}
+
+func (s *Supervisor) emit(e Event) {
+	select {
+	case s.events <- e:
+	default:
+	}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func trimWindow(ts []time.Time, now time.Time, window time.Duration) []time.Time {
+	if window <= 0 {
+		return ts
+	}
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(ts) && ts[i].Before(cutoff) {
+		i++
+	}
+	return ts[i:]
+}
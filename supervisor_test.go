@@ -0,0 +1,110 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestSupervisorRestartsOnFailure(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stdout, cmd.Stderr = nil, nil
+
+	name, args := shellScript(t, "exit 1")
+	sup := NewSupervisor(cmd, name, args...)
+	sup.Policy = RestartOnFailure
+	sup.Backoff.Initial = time.Millisecond
+	sup.Backoff.Max = time.Millisecond
+	sup.Backoff.Jitter = 0
+	sup.MaxRestarts = 3
+	sup.Window = time.Second
+
+	err := sup.Run(context.Background())
+	is.Equal(err == nil, false)
+
+	var started, exited, restarted, gaveUp int
+	for {
+		select {
+		case ev := <-sup.Events():
+			switch ev.Kind {
+			case EventStarted:
+				started++
+			case EventExited:
+				exited++
+			case EventRestarted:
+				restarted++
+			case EventGaveUp:
+				gaveUp++
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	is.Equal(started, 4) // initial run + 3 restarts
+	is.Equal(exited, 4)
+	is.Equal(restarted, 3)
+	is.Equal(gaveUp, 1)
+}
+
+func TestSupervisorManualRestart(t *testing.T) {
+	is := is.New(t)
+	cmd := New("")
+	cmd.Stdout, cmd.Stderr = nil, nil
+
+	name, args := sleepScript(t, 300)
+	sup := NewSupervisor(cmd, name, args...)
+	sup.Backoff.Initial = time.Millisecond
+	sup.Backoff.Max = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sup.Run(ctx) }()
+
+	// Wait for the first Started event before asking for a restart.
+	waitForEvent(t, sup, EventStarted)
+	sup.Restart()
+	waitForEvent(t, sup, EventRestarted)
+
+	cancel()
+	err := <-done
+	is.Equal(err, context.Canceled)
+}
+
+func waitForEvent(t *testing.T, sup *Supervisor, kind EventKind) {
+	t.Helper()
+	deadline := time.NewTimer(5 * time.Second)
+	defer deadline.Stop()
+	for {
+		select {
+		case ev := <-sup.Events():
+			if ev.Kind == kind {
+				return
+			}
+		case <-deadline.C:
+			t.Fatalf("timed out waiting for event %v", kind)
+		}
+	}
+}
+
+func shellScript(t *testing.T, script string) (name string, args []string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", script}
+	}
+	return "sh", []string{"-c", script}
+}
+
+func sleepScript(t *testing.T, seconds int) (name string, args []string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", "ping -n 6 127.0.0.1 >NUL"}
+	}
+	return "sh", []string{"-c", fmt.Sprintf("sleep %d", seconds)}
+}